@@ -0,0 +1,265 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package contenthash computes stable, content-addressable digests of
+// file trees, modelled on buildkit's contenthash package.  Unlike buildkit,
+// cached entries are kept in a flat map keyed by path, not in a radix
+// tree.  It lets newtmgr recognise that a directory (e.g.
+// a build artifact or image directory) is unchanged since it was last
+// checksummed, even across processes, without relying solely on mtimes.
+package contenthash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+
+	"mynewt.apache.org/newt/util"
+)
+
+// cacheEntry is one node in a CacheContext's content-hash tree: either a
+// file, whose Content digest covers its header and bytes, or a directory,
+// whose Content digest covers its own header plus the sorted Content
+// digests of its children.
+type cacheEntry struct {
+	Header  digest.Digest
+	Content digest.Digest
+	ModTime time.Time
+	IsDir   bool
+}
+
+// CacheContext amortises repeated Checksum calls against the same
+// directory tree: a file whose mtime and header haven't changed since it
+// was last checksummed is not reread or rehashed.
+type CacheContext struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry // keyed by path, cleaned relative to root
+}
+
+// NewCacheContext returns an empty CacheContext.
+func NewCacheContext() *CacheContext {
+	return &CacheContext{entries: map[string]*cacheEntry{}}
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*CacheContext{}
+)
+
+// GetCacheContext returns the CacheContext previously registered for root
+// via SetCacheContext, or nil if none has been registered.
+func GetCacheContext(root string) *CacheContext {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return registry[root]
+}
+
+// SetCacheContext registers cc as the CacheContext to reuse for
+// subsequent Checksum calls against root.
+func SetCacheContext(root string, cc *CacheContext) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[root] = cc
+}
+
+// Checksum computes a stable digest of the file or directory tree rooted
+// at filepath.Join(root, subpath), using (and populating) the
+// CacheContext registered for root via SetCacheContext, or a fresh one if
+// none is registered yet.
+func Checksum(fs util.FS, root string, subpath string) (digest.Digest, error) {
+	cc := GetCacheContext(root)
+	if cc == nil {
+		cc = NewCacheContext()
+		SetCacheContext(root, cc)
+	}
+	return cc.Checksum(fs, root, subpath)
+}
+
+// Checksum computes a stable digest of the file or directory tree rooted
+// at filepath.Join(root, subpath), reusing cc's cached entries where
+// their mtime and header are unchanged.
+func (cc *CacheContext) Checksum(fs util.FS, root string, subpath string) (
+	digest.Digest, error) {
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	clean := path.Clean("/" + filepath.ToSlash(subpath))
+	e, err := cc.checksumPath(fs, root, clean, map[string]bool{})
+	if err != nil {
+		return "", err
+	}
+	return e.Content, nil
+}
+
+// checksumPath computes (or reuses) the cacheEntry for rel, a path
+// cleaned relative to root, recursing into directories in sorted order
+// and following symlinks.  visited holds the cleaned, absolute targets of
+// symlinks followed so far on the current path, so a symlink that (directly
+// or indirectly) points back at one of them is caught as a cycle instead of
+// being followed until the OS itself gives up.
+func (cc *CacheContext) checksumPath(fs util.FS, root string, rel string,
+	visited map[string]bool) (*cacheEntry, error) {
+
+	full := filepath.Join(root, filepath.FromSlash(rel))
+
+	lst, err := fs.Lstat(full)
+	if err != nil {
+		return nil, util.NewNewtError(err.Error())
+	}
+
+	if lst.Mode()&os.ModeSymlink != 0 {
+		target, err := fs.Readlink(full)
+		if err != nil {
+			return nil, util.NewNewtError(err.Error())
+		}
+
+		resolved := target
+		if !filepath.IsAbs(resolved) {
+			resolved = filepath.Join(filepath.Dir(full), resolved)
+		}
+		resolved = filepath.Clean(resolved)
+
+		if visited[resolved] {
+			return nil, util.NewNewtError(fmt.Sprintf(
+				"contenthash: symlink cycle detected at %s", full))
+		}
+
+		childVisited := make(map[string]bool, len(visited)+1)
+		for k := range visited {
+			childVisited[k] = true
+		}
+		childVisited[resolved] = true
+
+		targetRel, err := filepath.Rel(root, resolved)
+		if err != nil {
+			targetRel = resolved
+		}
+
+		targetEntry, err := cc.checksumPath(fs, root, filepath.ToSlash(targetRel), childVisited)
+		if err != nil {
+			return nil, err
+		}
+
+		header := headerDigest(rel, lst, target)
+		e := &cacheEntry{
+			Header:  header,
+			Content: targetEntry.Content,
+			ModTime: lst.ModTime(),
+			IsDir:   targetEntry.IsDir,
+		}
+		cc.entries[rel] = e
+		return e, nil
+	}
+
+	header := headerDigest(rel, lst, "")
+
+	if !lst.IsDir() {
+		if old, ok := cc.entries[rel]; ok && !old.IsDir &&
+			old.Header == header && old.ModTime.Equal(lst.ModTime()) {
+			return old, nil
+		}
+
+		content, err := fileContentDigest(fs, full, header)
+		if err != nil {
+			return nil, err
+		}
+
+		e := &cacheEntry{Header: header, Content: content, ModTime: lst.ModTime()}
+		cc.entries[rel] = e
+		return e, nil
+	}
+
+	children, err := fs.ReadDir(full)
+	if err != nil {
+		return nil, util.NewNewtError(err.Error())
+	}
+
+	names := make([]string, 0, len(children))
+	for _, c := range children {
+		names = append(names, c.Name())
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	io.WriteString(h, string(header))
+	for _, name := range names {
+		child, err := cc.checksumPath(fs, root, path.Join(rel, name), visited)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", name, child.Content)
+	}
+
+	e := &cacheEntry{
+		Header:  header,
+		Content: digest.NewDigestFromEncoded(digest.SHA256, hex.EncodeToString(h.Sum(nil))),
+		ModTime: lst.ModTime(),
+		IsDir:   true,
+	}
+	cc.entries[rel] = e
+	return e, nil
+}
+
+// headerDigest hashes a path's metadata: mode, uid/gid, size, and symlink
+// target.  Directory headers hash the path with a trailing "/", as in
+// buildkit's contenthash.
+func headerDigest(rel string, info os.FileInfo, symlinkTarget string) digest.Digest {
+	name := rel
+	if info.IsDir() && !strings.HasSuffix(name, "/") {
+		name += "/"
+	}
+
+	uid, gid := fileOwner(info)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%o\x00%d\x00%d\x00%d\x00%s",
+		name, info.Mode(), uid, gid, info.Size(), symlinkTarget)
+
+	return digest.NewDigestFromEncoded(digest.SHA256, hex.EncodeToString(h.Sum(nil)))
+}
+
+// fileContentDigest hashes header followed by the file's contents.
+func fileContentDigest(fs util.FS, full string, header digest.Digest) (
+	digest.Digest, error) {
+
+	f, err := fs.Open(full)
+	if err != nil {
+		return "", util.NewNewtError(err.Error())
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	io.WriteString(h, string(header))
+	if _, err := io.Copy(h, f); err != nil {
+		return "", util.NewNewtError(err.Error())
+	}
+
+	return digest.NewDigestFromEncoded(digest.SHA256, hex.EncodeToString(h.Sum(nil))), nil
+}
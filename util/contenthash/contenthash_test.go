@@ -0,0 +1,85 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package contenthash
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"mynewt.apache.org/newt/util"
+)
+
+func TestChecksumIsStableAcrossCalls(t *testing.T) {
+	root, err := ioutil.TempDir("", "contenthash")
+	if err != nil {
+		t.Fatalf("TempDir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %s", err.Error())
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "sub", "file.txt"),
+		[]byte("content"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %s", err.Error())
+	}
+
+	cc := NewCacheContext()
+
+	d1, err := cc.Checksum(util.DefaultFS, root, "/")
+	if err != nil {
+		t.Fatalf("Checksum failed: %s", err.Error())
+	}
+	d2, err := cc.Checksum(util.DefaultFS, root, "/")
+	if err != nil {
+		t.Fatalf("Checksum failed: %s", err.Error())
+	}
+
+	if d1 != d2 {
+		t.Fatalf("checksum changed across calls with no tree changes: %s != %s",
+			d1, d2)
+	}
+}
+
+func TestChecksumDetectsSymlinkCycle(t *testing.T) {
+	root, err := ioutil.TempDir("", "contenthash")
+	if err != nil {
+		t.Fatalf("TempDir failed: %s", err.Error())
+	}
+	defer os.RemoveAll(root)
+
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %s", err.Error())
+	}
+	if err := os.Symlink(".", filepath.Join(root, "sub", "loop")); err != nil {
+		t.Fatalf("Symlink failed: %s", err.Error())
+	}
+
+	_, err = Checksum(util.DefaultFS, root, "/")
+	if err == nil {
+		t.Fatalf("expected a symlink cycle error, got nil")
+	}
+	if !strings.Contains(err.Error(), "symlink cycle detected") {
+		t.Fatalf("expected a symlink cycle error, got: %s", err.Error())
+	}
+}
@@ -0,0 +1,171 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package util
+
+import (
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// RunOpts controls how RunCmd executes a command.
+type RunOpts struct {
+	// Dir is the working directory the command runs in.  If empty, the
+	// current process's working directory is used.
+	Dir string
+
+	// Env, if non-nil, is appended to the command's environment.
+	Env []string
+}
+
+// RunCmd executes the command specified by argv (argv[0] is the program,
+// argv[1:] are its arguments) without invoking a shell, and returns its
+// combined stdout and stderr.
+func RunCmd(argv []string, opts RunOpts) ([]byte, error) {
+	if len(argv) == 0 {
+		return nil, NewNewtError("RunCmd: empty argv")
+	}
+
+	log.Print("[VERBOSE] " + strings.Join(argv, " "))
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = opts.Dir
+	if opts.Env != nil {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+
+	o, err := cmd.CombinedOutput()
+	log.Print("[VERBOSE] o=" + string(o))
+	if err != nil {
+		return o, NewNewtError(err.Error())
+	} else {
+		return o, nil
+	}
+}
+
+// RunCmdInteractive executes the command specified by argv (argv[0] is the
+// program, argv[1:] are its arguments) without invoking a shell, attaching
+// it to the current process's stdin, stdout, and stderr.  It is used for
+// commands that need a live terminal, e.g. a debugger.
+func RunCmdInteractive(argv []string, opts RunOpts) error {
+	if len(argv) == 0 {
+		return NewNewtError("RunCmdInteractive: empty argv")
+	}
+
+	log.Print("[VERBOSE] " + strings.Join(argv, " "))
+
+	//
+	// Block SIGINT, at least.
+	// Otherwise Ctrl-C meant for gdb would kill newt.
+	//
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, syscall.SIGTERM)
+	go func() {
+		<-c
+	}()
+	defer signal.Stop(c)
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = opts.Dir
+	if opts.Env != nil {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return NewNewtError(err.Error())
+	}
+
+	return nil
+}
+
+// CopyFile copies the contents of srcFile to destFile using pure-Go I/O
+// (via DefaultFS), creating destFile's parent directory if necessary.  If
+// srcFile is a directory, it is copied recursively via CopyDir.
+func CopyFile(srcFile string, destFile string) error {
+	if err := MkdirAll(filepath.Dir(destFile)); err != nil {
+		return err
+	}
+
+	info, err := DefaultFS.Stat(srcFile)
+	if err != nil {
+		return NewNewtError(err.Error())
+	}
+	if info.IsDir() {
+		return CopyDir(srcFile, destFile)
+	}
+
+	src, err := DefaultFS.Open(srcFile)
+	if err != nil {
+		return NewNewtError(err.Error())
+	}
+	defer src.Close()
+
+	dst, err := DefaultFS.OpenFile(destFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC,
+		info.Mode())
+	if err != nil {
+		return NewNewtError(err.Error())
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return NewNewtError(err.Error())
+	}
+
+	return nil
+}
+
+// CopyDir recursively copies the directory tree rooted at srcDir to
+// destDir using pure-Go I/O (via DefaultFS), preserving file modes.
+func CopyDir(srcDir, destDir string) error {
+	return DefaultFS.Walk(srcDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return NewNewtError(err.Error())
+		}
+
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return NewNewtError(err.Error())
+		}
+		target := filepath.Join(destDir, rel)
+
+		if info.IsDir() {
+			return MkdirAll(target)
+		}
+
+		return CopyFile(p, target)
+	})
+}
+
+// MkdirAll creates the named directory, along with any necessary parents,
+// using pure-Go I/O (via DefaultFS).
+func MkdirAll(path string) error {
+	if err := DefaultFS.MkdirAll(path, 0755); err != nil {
+		return NewNewtError(err.Error())
+	}
+	return nil
+}
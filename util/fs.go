@@ -0,0 +1,111 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// File is the subset of *os.File that util.FS implementations hand back.
+// *os.File already satisfies this interface.
+type File interface {
+	Name() string
+	Read(p []byte) (int, error)
+	Write(p []byte) (int, error)
+	Seek(offset int64, whence int) (int64, error)
+	Close() error
+	Readdir(count int) ([]os.FileInfo, error)
+	Stat() (os.FileInfo, error)
+}
+
+// FS abstracts the filesystem calls made throughout this package, modelled
+// on spf13/afero.  OsFS is the real, disk-backed implementation; MemFS is
+// an in-memory implementation intended for unit tests.  Packages that want
+// to be testable without touching disk should thread a util.FS through
+// rather than calling the os/ioutil functions directly.
+type FS interface {
+	Stat(name string) (os.FileInfo, error)
+	Lstat(name string) (os.FileInfo, error)
+	Readlink(name string) (string, error)
+	Open(name string) (File, error)
+	Create(name string) (File, error)
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	ReadDir(dirname string) ([]os.FileInfo, error)
+	MkdirAll(path string, perm os.FileMode) error
+	Remove(name string) error
+	Rename(oldname string, newname string) error
+	Walk(root string, walkFn filepath.WalkFunc) error
+}
+
+// DefaultFS is the FS implementation used by this package's helper
+// functions.  Tests may replace it (or pass a MemFS explicitly) so that
+// filesystem-touching code can be exercised without disk I/O.
+var DefaultFS FS = OsFS{}
+
+// OsFS is the FS implementation backed by the real operating system
+// filesystem; it is the behavior this package had before FS was
+// introduced.
+type OsFS struct{}
+
+func (OsFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (OsFS) Lstat(name string) (os.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+func (OsFS) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+func (OsFS) Open(name string) (File, error) {
+	return os.Open(name)
+}
+
+func (OsFS) Create(name string) (File, error) {
+	return os.Create(name)
+}
+
+func (OsFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (OsFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(dirname)
+}
+
+func (OsFS) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (OsFS) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func (OsFS) Rename(oldname string, newname string) error {
+	return os.Rename(oldname, newname)
+}
+
+func (OsFS) Walk(root string, walkFn filepath.WalkFunc) error {
+	return filepath.Walk(root, walkFn)
+}
@@ -0,0 +1,156 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestMemFSMkdirAllAndReadDir(t *testing.T) {
+	m := NewMemFS()
+
+	if err := m.MkdirAll("/a/b/c", 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %s", err.Error())
+	}
+
+	infos, err := m.ReadDir("/a/b")
+	if err != nil {
+		t.Fatalf("ReadDir failed: %s", err.Error())
+	}
+	if len(infos) != 1 || infos[0].Name() != "c" || !infos[0].IsDir() {
+		t.Fatalf("unexpected ReadDir result: %+v", infos)
+	}
+}
+
+func TestMemFSCreateAndOpen(t *testing.T) {
+	m := NewMemFS()
+
+	if err := m.MkdirAll("/dir", 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %s", err.Error())
+	}
+
+	f, err := m.Create("/dir/file.txt")
+	if err != nil {
+		t.Fatalf("Create failed: %s", err.Error())
+	}
+	if _, err := f.Write([]byte("hello")); err != nil {
+		t.Fatalf("Write failed: %s", err.Error())
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close failed: %s", err.Error())
+	}
+
+	r, err := m.Open("/dir/file.txt")
+	if err != nil {
+		t.Fatalf("Open failed: %s", err.Error())
+	}
+	defer r.Close()
+
+	buf := make([]byte, 5)
+	if _, err := r.Read(buf); err != nil {
+		t.Fatalf("Read failed: %s", err.Error())
+	}
+	if string(buf) != "hello" {
+		t.Fatalf("expected \"hello\", got %q", string(buf))
+	}
+}
+
+func TestMemFSWalkSkipDirStopsRemainingSiblings(t *testing.T) {
+	m := NewMemFS()
+
+	if err := m.MkdirAll("/dir", 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %s", err.Error())
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		f, err := m.Create("/dir/" + name)
+		if err != nil {
+			t.Fatalf("Create failed: %s", err.Error())
+		}
+		f.Close()
+	}
+
+	var visited []string
+	err := m.Walk("/dir", func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		visited = append(visited, info.Name())
+		if info.Name() == "a" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %s", err.Error())
+	}
+
+	sort.Strings(visited)
+	if len(visited) != 1 || visited[0] != "a" {
+		t.Fatalf("expected only \"a\" to be visited, got %v", visited)
+	}
+}
+
+func TestCopyFileAndCopyDirUseDefaultFS(t *testing.T) {
+	orig := DefaultFS
+	defer func() { DefaultFS = orig }()
+
+	m := NewMemFS()
+	DefaultFS = m
+
+	if err := m.MkdirAll("/src/sub", 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %s", err.Error())
+	}
+	f, err := m.Create("/src/sub/file.txt")
+	if err != nil {
+		t.Fatalf("Create failed: %s", err.Error())
+	}
+	if _, err := f.Write([]byte("content")); err != nil {
+		t.Fatalf("Write failed: %s", err.Error())
+	}
+	f.Close()
+
+	if err := CopyFile("/src/sub/file.txt", "/dst/sub/file.txt"); err != nil {
+		t.Fatalf("CopyFile failed: %s", err.Error())
+	}
+
+	r, err := m.Open("/dst/sub/file.txt")
+	if err != nil {
+		t.Fatalf("copied file missing: %s", err.Error())
+	}
+	buf := make([]byte, 7)
+	r.Read(buf)
+	r.Close()
+	if string(buf) != "content" {
+		t.Fatalf("expected \"content\", got %q", string(buf))
+	}
+
+	if err := CopyDir("/src", "/dst2"); err != nil {
+		t.Fatalf("CopyDir failed: %s", err.Error())
+	}
+	if _, err := m.Stat("/dst2/sub/file.txt"); err != nil {
+		t.Fatalf("CopyDir did not recreate nested file: %s", err.Error())
+	}
+}
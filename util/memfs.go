@@ -0,0 +1,349 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package util
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// memNode is one entry (file or directory) in a MemFS tree.
+type memNode struct {
+	name     string
+	isDir    bool
+	mode     os.FileMode
+	modTime  time.Time
+	content  []byte
+	children map[string]*memNode
+}
+
+func (n *memNode) Info() os.FileInfo {
+	return memFileInfo{node: n}
+}
+
+type memFileInfo struct {
+	node *memNode
+}
+
+func (fi memFileInfo) Name() string       { return fi.node.name }
+func (fi memFileInfo) Size() int64        { return int64(len(fi.node.content)) }
+func (fi memFileInfo) Mode() os.FileMode  { return fi.node.mode }
+func (fi memFileInfo) ModTime() time.Time { return fi.node.modTime }
+func (fi memFileInfo) IsDir() bool        { return fi.node.isDir }
+func (fi memFileInfo) Sys() interface{}   { return nil }
+
+// MemFS is an in-memory FS implementation, for use in tests that need
+// filesystem-shaped behavior without touching disk.
+type MemFS struct {
+	root *memNode
+}
+
+// NewMemFS returns an empty in-memory filesystem rooted at "/".
+func NewMemFS() *MemFS {
+	return &MemFS{
+		root: &memNode{
+			name:     "/",
+			isDir:    true,
+			mode:     os.ModeDir | 0755,
+			children: map[string]*memNode{},
+		},
+	}
+}
+
+func memPathErr(op string, name string) error {
+	return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+}
+
+func splitMemPath(name string) []string {
+	clean := path.Clean("/" + filepath.ToSlash(name))
+	if clean == "/" {
+		return nil
+	}
+	return strings.Split(strings.TrimPrefix(clean, "/"), "/")
+}
+
+func (m *MemFS) lookup(name string) (*memNode, error) {
+	cur := m.root
+	for _, p := range splitMemPath(name) {
+		if !cur.isDir {
+			return nil, memPathErr("open", name)
+		}
+		child, ok := cur.children[p]
+		if !ok {
+			return nil, memPathErr("open", name)
+		}
+		cur = child
+	}
+	return cur, nil
+}
+
+// lookupParent resolves the parent directory of name, returning it along
+// with name's base component.
+func (m *MemFS) lookupParent(name string) (*memNode, string, error) {
+	parts := splitMemPath(name)
+	if len(parts) == 0 {
+		return nil, "", memPathErr("open", name)
+	}
+
+	cur := m.root
+	for _, p := range parts[:len(parts)-1] {
+		child, ok := cur.children[p]
+		if !ok || !child.isDir {
+			return nil, "", memPathErr("open", name)
+		}
+		cur = child
+	}
+	return cur, parts[len(parts)-1], nil
+}
+
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	node, err := m.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return node.Info(), nil
+}
+
+// Lstat is equivalent to Stat: MemFS has no symlink support, so there is
+// never a link to stop short of following.
+func (m *MemFS) Lstat(name string) (os.FileInfo, error) {
+	return m.Stat(name)
+}
+
+// Readlink always fails: MemFS has no symlink support.
+func (m *MemFS) Readlink(name string) (string, error) {
+	if _, err := m.lookup(name); err != nil {
+		return "", err
+	}
+	return "", memPathErr("readlink", name)
+}
+
+func (m *MemFS) Open(name string) (File, error) {
+	node, err := m.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return &memFile{node: node, reader: bytes.NewReader(node.content)}, nil
+}
+
+func (m *MemFS) Create(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+}
+
+func (m *MemFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	node, err := m.lookup(name)
+	if err != nil {
+		if flag&os.O_CREATE == 0 {
+			return nil, err
+		}
+		parent, base, perr := m.lookupParent(name)
+		if perr != nil {
+			return nil, perr
+		}
+		node = &memNode{name: base, mode: perm, modTime: time.Now()}
+		parent.children[base] = node
+	}
+
+	buf := new(bytes.Buffer)
+	if flag&os.O_TRUNC == 0 {
+		buf.Write(node.content)
+	}
+
+	return &memFile{
+		node:     node,
+		reader:   bytes.NewReader(node.content),
+		buf:      buf,
+		writable: true,
+	}, nil
+}
+
+func (m *MemFS) ReadDir(dirname string) ([]os.FileInfo, error) {
+	node, err := m.lookup(dirname)
+	if err != nil {
+		return nil, err
+	}
+	if !node.isDir {
+		return nil, memPathErr("readdir", dirname)
+	}
+
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := make([]os.FileInfo, 0, len(names))
+	for _, name := range names {
+		infos = append(infos, node.children[name].Info())
+	}
+	return infos, nil
+}
+
+func (m *MemFS) MkdirAll(p string, perm os.FileMode) error {
+	cur := m.root
+	for _, name := range splitMemPath(p) {
+		child, ok := cur.children[name]
+		if !ok {
+			child = &memNode{
+				name:     name,
+				isDir:    true,
+				mode:     os.ModeDir | perm,
+				modTime:  time.Now(),
+				children: map[string]*memNode{},
+			}
+			cur.children[name] = child
+		} else if !child.isDir {
+			return memPathErr("mkdir", p)
+		}
+		cur = child
+	}
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	parent, base, err := m.lookupParent(name)
+	if err != nil {
+		return err
+	}
+	if _, ok := parent.children[base]; !ok {
+		return memPathErr("remove", name)
+	}
+	delete(parent.children, base)
+	return nil
+}
+
+func (m *MemFS) Rename(oldname string, newname string) error {
+	node, err := m.lookup(oldname)
+	if err != nil {
+		return err
+	}
+	oldParent, oldBase, err := m.lookupParent(oldname)
+	if err != nil {
+		return err
+	}
+	newParent, newBase, err := m.lookupParent(newname)
+	if err != nil {
+		return err
+	}
+
+	delete(oldParent.children, oldBase)
+	node.name = newBase
+	newParent.children[newBase] = node
+	return nil
+}
+
+func (m *MemFS) Walk(root string, walkFn filepath.WalkFunc) error {
+	node, err := m.lookup(root)
+	if err != nil {
+		return walkFn(root, nil, err)
+	}
+	return m.walk(root, node, walkFn)
+}
+
+func (m *MemFS) walk(p string, node *memNode, walkFn filepath.WalkFunc) error {
+	if err := walkFn(p, node.Info(), nil); err != nil {
+		if node.isDir && err == filepath.SkipDir {
+			return nil
+		}
+		return err
+	}
+	if !node.isDir {
+		return nil
+	}
+
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		childPath := path.Join(p, name)
+		if err := m.walk(childPath, node.children[name], walkFn); err != nil {
+			if err == filepath.SkipDir {
+				// A SkipDir returned for a non-directory entry means
+				// "stop walking the rest of this directory", matching
+				// filepath.Walk; a directory entry's own SkipDir is
+				// already absorbed inside the recursive call above.
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// memFile is the File implementation returned by MemFS.
+type memFile struct {
+	node     *memNode
+	reader   *bytes.Reader
+	buf      *bytes.Buffer
+	writable bool
+}
+
+func (f *memFile) Name() string {
+	return f.node.name
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	return f.reader.Read(p)
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if !f.writable {
+		return 0, memPathErr("write", f.node.name)
+	}
+	return f.buf.Write(p)
+}
+
+func (f *memFile) Seek(offset int64, whence int) (int64, error) {
+	return f.reader.Seek(offset, whence)
+}
+
+func (f *memFile) Close() error {
+	if f.writable {
+		f.node.content = f.buf.Bytes()
+		f.node.modTime = time.Now()
+	}
+	return nil
+}
+
+func (f *memFile) Readdir(count int) ([]os.FileInfo, error) {
+	names := make([]string, 0, len(f.node.children))
+	for name := range f.node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := make([]os.FileInfo, 0, len(names))
+	for _, name := range names {
+		infos = append(infos, f.node.children[name].Info())
+	}
+	return infos, nil
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	return f.node.Info(), nil
+}
@@ -23,17 +23,13 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"os"
-	"os/exec"
-	"os/signal"
 	"path"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/hashicorp/logutils"
@@ -90,7 +86,7 @@ func ErrorMessage(level int, message string, args ...interface{}) {
 }
 
 func NodeExist(path string) bool {
-	if _, err := os.Stat(path); err == nil {
+	if _, err := DefaultFS.Stat(path); err == nil {
 		return true
 	} else {
 		return false
@@ -99,7 +95,7 @@ func NodeExist(path string) bool {
 
 // Check whether the node (either dir or file) specified by path exists
 func NodeNotExist(path string) bool {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
+	if _, err := DefaultFS.Stat(path); os.IsNotExist(err) {
 		return true
 	} else {
 		return false
@@ -107,7 +103,7 @@ func NodeNotExist(path string) bool {
 }
 
 func FileModificationTime(path string) (time.Time, error) {
-	fileInfo, err := os.Stat(path)
+	fileInfo, err := DefaultFS.Stat(path)
 	if err != nil {
 		epoch := time.Unix(0, 0)
 		if os.IsNotExist(err) {
@@ -121,7 +117,7 @@ func FileModificationTime(path string) (time.Time, error) {
 }
 
 func ChildDirs(path string) ([]string, error) {
-	children, err := ioutil.ReadDir(path)
+	children, err := DefaultFS.ReadDir(path)
 	if err != nil {
 		return nil, NewNewtError(err.Error())
 	}
@@ -186,7 +182,10 @@ func Init(level string, verbosity int, logFile string) error {
 }
 
 // Read in the configuration file specified by name, in path
-// return a new viper config object if successful, and error if not
+// return a new viper config object if successful, and error if not.
+//
+// Note: viper performs its own file I/O internally, so this call does not
+// go through util.FS/DefaultFS like the other helpers in this file.
 func ReadConfig(path string, name string) (*viper.Viper, error) {
 	v := viper.New()
 	v.SetConfigType("yaml")
@@ -239,78 +238,10 @@ func DescendantDirsOfParent(rootPath string, parentName string, fullPath bool) (
 	return dirs, nil
 }
 
-// Execute the command specified by cmdStr on the shell and return results
-func ShellCommand(cmdStr string) ([]byte, error) {
-	log.Print("[VERBOSE] " + cmdStr)
-	cmd := exec.Command("sh", "-c", cmdStr)
-
-	o, err := cmd.CombinedOutput()
-	log.Print("[VERBOSE] o=" + string(o))
-	if err != nil {
-		return o, NewNewtError(err.Error())
-	} else {
-		return o, nil
-	}
-}
-
-// Run interactive shell command
-func ShellInteractiveCommand(cmdStr []string) error {
-	log.Print("[VERBOSE] " + cmdStr[0])
-
-	//
-	// Block SIGINT, at least.
-	// Otherwise Ctrl-C meant for gdb would kill newt.
-	//
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	signal.Notify(c, syscall.SIGTERM)
-	go func() {
-		<-c
-	}()
-
-	// Transfer stdin, stdout, and stderr to the new process
-	// and also set target directory for the shell to start in.
-	pa := os.ProcAttr{
-		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
-	}
-
-	// Start up a new shell.
-	proc, err := os.StartProcess(cmdStr[0], cmdStr, &pa)
-	if err != nil {
-		signal.Stop(c)
-		return NewNewtError(err.Error())
-	}
-
-	// Release and exit
-	_, err = proc.Wait()
-	if err != nil {
-		signal.Stop(c)
-		return NewNewtError(err.Error())
-	}
-	signal.Stop(c)
-	return nil
-}
-
-func CopyFile(srcFile string, destFile string) error {
-	_, err := ShellCommand(fmt.Sprintf("mkdir -p %s", filepath.Dir(destFile)))
-	if err != nil {
-		return err
-	}
-	if _, err := ShellCommand(fmt.Sprintf("cp -Rf %s %s", srcFile,
-		destFile)); err != nil {
-		return err
-	}
-	return nil
-}
-
-func CopyDir(srcDir, destDir string) error {
-	return CopyFile(srcDir, destDir)
-}
-
 // Reads each line from the specified text file into an array of strings.  If a
 // line ends with a backslash, it is concatenated with the following line.
 func ReadLines(path string) ([]string, error) {
-	file, err := os.Open(path)
+	file, err := DefaultFS.Open(path)
 	if err != nil {
 		return nil, NewNewtError(err.Error())
 	}